@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	commonconfig "github.com/prometheus/common/config"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MatchType selects how a MetricMapping's Match pattern is interpreted,
+// mirroring statsd_exporter's mapping config.
+type MatchType string
+
+const (
+	MatchTypeGlob  MatchType = "glob"
+	MatchTypeRegex MatchType = "regex"
+)
+
+// MetricType is the Prometheus metric type a mapping (or a target's
+// defaults) produces for a matched JSON value.
+type MetricType string
+
+const (
+	MetricTypeGauge     MetricType = "gauge"
+	MetricTypeCounter   MetricType = "counter"
+	MetricTypeHistogram MetricType = "histogram"
+	MetricTypeUntyped   MetricType = "untyped"
+)
+
+// MappingDefaults holds the fall-back type/buckets/ttl applied to a
+// metric name that did not match any explicit mapping.
+type MappingDefaults struct {
+	Type    MetricType    `yaml:"type,omitempty"`
+	Buckets []float64     `yaml:"buckets,omitempty"`
+	TTL     time.Duration `yaml:"ttl,omitempty"`
+}
+
+// MetricMapping renames/types a metric whose flattened JSON path name
+// matches Match, the same way a statsd_exporter mapping line does.
+type MetricMapping struct {
+	Match     string        `yaml:"match"`
+	MatchType MatchType     `yaml:"match_type,omitempty"`
+	Name      string        `yaml:"name,omitempty"`
+	Type      MetricType    `yaml:"type,omitempty"`
+	Buckets   []float64     `yaml:"buckets,omitempty"`
+	TTL       time.Duration `yaml:"ttl,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// compile turns Match (glob or regex, per MatchType) into a usable
+// *regexp.Regexp and validates the declared metric Type.
+func (m *MetricMapping) compile() error {
+	if m.Match == "" {
+		return fmt.Errorf("mapping is missing a match pattern")
+	}
+	switch m.MatchType {
+	case "", MatchTypeGlob:
+		quoted := regexp.QuoteMeta(m.Match)
+		quoted = strings.Replace(quoted, `\*`, `.*`, -1)
+		re, err := regexp.Compile("^" + quoted + "$")
+		if err != nil {
+			return fmt.Errorf("invalid glob match %q: %s", m.Match, err)
+		}
+		m.regex = re
+	case MatchTypeRegex:
+		re, err := regexp.Compile(m.Match)
+		if err != nil {
+			return fmt.Errorf("invalid regex match %q: %s", m.Match, err)
+		}
+		m.regex = re
+	default:
+		return fmt.Errorf("unknown match_type %q, must be glob or regex", m.MatchType)
+	}
+
+	switch m.Type {
+	case "", MetricTypeGauge, MetricTypeCounter, MetricTypeHistogram, MetricTypeUntyped:
+	default:
+		return fmt.Errorf("unknown metric type %q for match %q", m.Type, m.Match)
+	}
+	return nil
+}
+
+// TargetConfig describes a single JSON endpoint to scrape: where to get
+// it, how to turn its payload into labels, and how each extracted value
+// should be named, typed and expired.
+type TargetConfig struct {
+	Name        string            `yaml:"name"`
+	URL         string            `yaml:"url,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Blacklist   string            `yaml:"blacklist,omitempty"`
+	Whitelist   string            `yaml:"whitelist,omitempty"`
+	PathLabels  string            `yaml:"path_labels,omitempty"`
+	ValueLabels string            `yaml:"value_labels,omitempty"`
+	JMX         bool              `yaml:"jmx,omitempty"`
+	Lowercase   bool              `yaml:"lowercase,omitempty"`
+
+	// HTTPClientConfig carries auth/TLS/proxy settings for scraping this
+	// target's URL - basic_auth, bearer_token(_file), authorization,
+	// proxy_url and tls_config - inlined the same way Prometheus scrape
+	// configs do.
+	HTTPClientConfig commonconfig.HTTPClientConfig `yaml:",inline"`
+
+	Defaults MappingDefaults  `yaml:"defaults,omitempty"`
+	Mappings []*MetricMapping `yaml:"mappings,omitempty"`
+
+	// Metrics declares targeted JSONPath-based extraction rules. When
+	// non-empty, these replace the recursive "walk every key" extraction
+	// for this target entirely - see JSONPathMetric.
+	Metrics []*JSONPathMetric `yaml:"metrics,omitempty"`
+}
+
+// Config is the top level `--config.file` document: a list of scrape
+// targets, each configured and typed independently.
+type Config struct {
+	Targets []*TargetConfig `yaml:"targets"`
+}
+
+// lookupMapping returns the first mapping whose pattern matches name, or
+// nil if none do - in which case a target's Defaults apply.
+func (t *TargetConfig) lookupMapping(name string) *MetricMapping {
+	for _, m := range t.Mappings {
+		if m.regex != nil && m.regex.MatchString(name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads and validates a YAML mapping config from filename.
+func LoadConfig(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %s", filename, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", filename, err)
+	}
+
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("config file %s defines no targets", filename)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory of config file %s: %s", filename, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, target := range cfg.Targets {
+		// A target's url may be left empty only for --once --input.file
+		// use, which reads its payload from a file/stdin and never
+		// scrapes a URL - but then it needs an explicit name, since
+		// there's no URL to derive one from.
+		if target.URL == "" && target.Name == "" {
+			return nil, fmt.Errorf("target #%d needs a name since it has no url", i)
+		}
+		if target.Name == "" {
+			target.Name = defaultTargetName(target.URL)
+		}
+		if seen[target.Name] {
+			return nil, fmt.Errorf("duplicate target name %q", target.Name)
+		}
+		seen[target.Name] = true
+
+		// Relative password_file/bearer_token_file/tls paths resolve
+		// against the config file's directory, not the process cwd.
+		target.HTTPClientConfig.SetDirectory(dir)
+		if err := target.HTTPClientConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("target %q: invalid http client config: %s", target.Name, err)
+		}
+
+		for _, m := range target.Mappings {
+			if err := m.compile(); err != nil {
+				return nil, fmt.Errorf("target %q: %s", target.Name, err)
+			}
+		}
+
+		for _, jm := range target.Metrics {
+			if err := jm.compile(); err != nil {
+				return nil, fmt.Errorf("target %q: %s", target.Name, err)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// defaultTargetName derives a module name from a target URL when the
+// config doesn't set one explicitly. An empty URL (the --once
+// --input.file case, which never scrapes a URL) falls back to a fixed
+// name rather than an empty one.
+//
+// The name includes the host, not just the URL's path: two otherwise
+// ordinary URLs for different hosts commonly share a path (e.g.
+// every host in a fleet serving /metrics.json), and a path-only name
+// would make them collide.
+func defaultTargetName(rawurl string) string {
+	if rawurl == "" {
+		return "input_file"
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+	name := u.Host + u.Path
+	if name == "" {
+		return rawurl
+	}
+	return name
+}
+
+// uniqueTargetName returns name, or name suffixed with a counter if it's
+// already present in seen - covering the rare case where two targets
+// still synthesize the same default name (e.g. the same URL given
+// twice). seen is updated with whichever name is returned.
+func uniqueTargetName(name string, seen map[string]bool) string {
+	if !seen[name] {
+		seen[name] = true
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + "-" + strconv.Itoa(i)
+		if !seen[candidate] {
+			seen[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// defaultConfig synthesizes the single-target Config that the classic
+// CLI flags used to describe directly, so `--config.file` stays optional.
+func defaultConfig(urls []string, namespace string, labels []string, labelvalues []string, blacklist string, whitelist string, pathlabels string, valuelabels string, jmx bool, lowercase bool, httpClientConfig commonconfig.HTTPClientConfig) *Config {
+	staticLabels := make(map[string]string, len(labels))
+	for i, label := range labels {
+		if i < len(labelvalues) {
+			staticLabels[label] = labelvalues[i]
+		}
+	}
+
+	cfg := &Config{}
+	seen := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		cfg.Targets = append(cfg.Targets, &TargetConfig{
+			Name:             uniqueTargetName(defaultTargetName(u), seen),
+			URL:              u,
+			Namespace:        namespace,
+			Labels:           staticLabels,
+			Blacklist:        blacklist,
+			Whitelist:        whitelist,
+			PathLabels:       pathlabels,
+			ValueLabels:      valuelabels,
+			JMX:              jmx,
+			Lowercase:        lowercase,
+			HTTPClientConfig: httpClientConfig,
+		})
+	}
+	return cfg
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// writeTempConfig writes content to a temp YAML file and returns its path,
+// removing it when the test finishes.
+func writeTempConfig(t *testing.T, content string) string {
+	t.Helper()
+	tmp, err := ioutil.TempFile("", "config_test_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	t.Cleanup(func() { os.Remove(tmp.Name()) })
+	if _, err := tmp.WriteString(content); err != nil {
+		t.Fatalf("failed to write temp file: %s", err)
+	}
+	tmp.Close()
+	return tmp.Name()
+}
+
+func TestLoadConfigParsesFixtureWithGlobAndRegexMappings(t *testing.T) {
+	path := writeTempConfig(t, `
+targets:
+  - name: svc1
+    url: http://host1:9100/metrics.json
+    defaults:
+      type: gauge
+      ttl: 30s
+    mappings:
+      - match: "*_total"
+        type: counter
+      - match: "^latency_(p50|p99)$"
+        match_type: regex
+        ttl: 5s
+  - url: http://host2:9100/metrics.json
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: unexpected error: %s", err)
+	}
+	if len(cfg.Targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(cfg.Targets))
+	}
+
+	svc1 := cfg.Targets[0]
+	if svc1.Name != "svc1" {
+		t.Errorf("expected explicit name to be kept, got %q", svc1.Name)
+	}
+	if got := svc1.lookupMapping("requests_total"); got == nil || got.Type != MetricTypeCounter {
+		t.Errorf("expected the glob mapping to match requests_total as a counter, got %+v", got)
+	}
+	if got := svc1.lookupMapping("latency_p99"); got == nil || got.TTL != 5*time.Second {
+		t.Errorf("expected the regex mapping to match latency_p99 with a 5s ttl, got %+v", got)
+	}
+	if got := svc1.lookupMapping("unrelated_field"); got != nil {
+		t.Errorf("expected no mapping to match unrelated_field, got %+v", got)
+	}
+
+	// The second target has no explicit name, so it falls back to
+	// defaultTargetName - host+path, derived the same way the legacy
+	// CLI flags synthesize a name.
+	if cfg.Targets[1].Name != "host2:9100/metrics.json" {
+		t.Errorf("expected a derived name for the url-only target, got %q", cfg.Targets[1].Name)
+	}
+}
+
+func TestLoadConfigRejectsDuplicateNames(t *testing.T) {
+	path := writeTempConfig(t, `
+targets:
+  - name: dup
+    url: http://host1:9100/metrics.json
+  - name: dup
+    url: http://host2:9100/metrics.json
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected LoadConfig to reject duplicate target names")
+	}
+}
+
+func TestLoadConfigRejectsTargetWithNoURLOrName(t *testing.T) {
+	path := writeTempConfig(t, `
+targets:
+  - defaults:
+      type: gauge
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected LoadConfig to reject a target with neither url nor name")
+	}
+}
+
+func TestLoadConfigRejectsInvalidMapping(t *testing.T) {
+	path := writeTempConfig(t, `
+targets:
+  - name: svc1
+    url: http://host1:9100/metrics.json
+    mappings:
+      - match: "requests_total"
+        type: nonsense
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected LoadConfig to reject an unknown mapping type")
+	}
+}
+
+func TestLoadConfigRejectsEmptyTargetsList(t *testing.T) {
+	path := writeTempConfig(t, "targets: []\n")
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected LoadConfig to reject a config defining no targets")
+	}
+}
+
+func TestMetricMappingCompileGlob(t *testing.T) {
+	m := &MetricMapping{Match: "requests_*"}
+	if err := m.compile(); err != nil {
+		t.Fatalf("compile: unexpected error: %s", err)
+	}
+	if !m.regex.MatchString("requests_total") {
+		t.Error("expected the glob to match requests_total")
+	}
+	if m.regex.MatchString("other_requests_total") {
+		t.Error("expected the glob to anchor at both ends, not match other_requests_total")
+	}
+}
+
+func TestMetricMappingCompileRegex(t *testing.T) {
+	m := &MetricMapping{Match: "^latency_(p50|p99)$", MatchType: MatchTypeRegex}
+	if err := m.compile(); err != nil {
+		t.Fatalf("compile: unexpected error: %s", err)
+	}
+	if !m.regex.MatchString("latency_p99") {
+		t.Error("expected the regex to match latency_p99")
+	}
+	if m.regex.MatchString("latency_p999") {
+		t.Error("expected the anchored regex not to match latency_p999")
+	}
+}
+
+func TestMetricMappingCompileRejectsUnknownMatchType(t *testing.T) {
+	m := &MetricMapping{Match: "foo", MatchType: "weird"}
+	if err := m.compile(); err == nil {
+		t.Error("expected compile to reject an unknown match_type")
+	}
+}
+
+func TestMetricMappingCompileRejectsUnknownType(t *testing.T) {
+	m := &MetricMapping{Match: "foo", Type: "weird"}
+	if err := m.compile(); err == nil {
+		t.Error("expected compile to reject an unknown metric type")
+	}
+}
+
+func TestAddMetricMappingOverridesTypeButKeepsDefaultTTL(t *testing.T) {
+	cfg := &TargetConfig{
+		Name:      "t",
+		Namespace: "ns",
+		Defaults:  MappingDefaults{Type: MetricTypeGauge, TTL: 30 * time.Second},
+		Mappings: []*MetricMapping{
+			{Match: "requests_total", Type: MetricTypeCounter},
+		},
+	}
+	for _, m := range cfg.Mappings {
+		if err := m.compile(); err != nil {
+			t.Fatalf("compile: unexpected error: %s", err)
+		}
+	}
+
+	tc, err := newTargetCollector(cfg, time.Second, false)
+	if err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	tc.addMetric("requests_total", 5, "help")
+
+	fam, ok := tc.families["requests_total"]
+	if !ok {
+		t.Fatal("expected a metric family for requests_total")
+	}
+	// The mapping overrides the type but leaves ttl unset, so the
+	// target's default ttl should still apply.
+	if fam.mtype != MetricTypeCounter {
+		t.Errorf("expected the mapping's counter type to win, got %q", fam.mtype)
+	}
+	if fam.ttl != 30*time.Second {
+		t.Errorf("expected the default ttl to apply since the mapping didn't set one, got %s", fam.ttl)
+	}
+}
+
+func TestAddMetricMappingOverridesTTL(t *testing.T) {
+	cfg := &TargetConfig{
+		Name:      "t",
+		Namespace: "ns",
+		Defaults:  MappingDefaults{Type: MetricTypeGauge, TTL: 30 * time.Second},
+		Mappings: []*MetricMapping{
+			{Match: "latency_*", TTL: 5 * time.Second},
+		},
+	}
+	for _, m := range cfg.Mappings {
+		if err := m.compile(); err != nil {
+			t.Fatalf("compile: unexpected error: %s", err)
+		}
+	}
+
+	tc, err := newTargetCollector(cfg, time.Second, false)
+	if err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	tc.addMetric("latency_p99", 12, "help")
+
+	fam, ok := tc.families["latency_p99"]
+	if !ok {
+		t.Fatal("expected a metric family for latency_p99")
+	}
+	if fam.ttl != 5*time.Second {
+		t.Errorf("expected the mapping's ttl to win over the default, got %s", fam.ttl)
+	}
+	if fam.mtype != MetricTypeGauge {
+		t.Errorf("expected the target's default type since the mapping didn't set one, got %q", fam.mtype)
+	}
+}
+
+func TestDefaultTargetNameIncludesHost(t *testing.T) {
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{"", "input_file"},
+		{"http://host1:9100/metrics.json", "host1:9100/metrics.json"},
+		{"http://host2:9100/metrics.json", "host2:9100/metrics.json"},
+		{"not a url", "not a url"},
+	}
+	for _, c := range cases {
+		if got := defaultTargetName(c.url); got != c.want {
+			t.Errorf("defaultTargetName(%q) = %q, want %q", c.url, got, c.want)
+		}
+	}
+}
+
+func TestDefaultConfigKeepsSameHostDifferentPathSeparate(t *testing.T) {
+	urls := []string{"http://host1:9100/metrics.json", "http://host2:9100/metrics.json"}
+	cfg := defaultConfig(urls, "ns", nil, nil, "", "", "", "", false, false, commonconfig.HTTPClientConfig{})
+	if cfg.Targets[0].Name == cfg.Targets[1].Name {
+		t.Fatalf("expected distinct names for distinct hosts, got %q for both", cfg.Targets[0].Name)
+	}
+}
+
+func TestDefaultConfigDeduplicatesIdenticalURLs(t *testing.T) {
+	urls := []string{"http://host1:9100/metrics.json", "http://host1:9100/metrics.json"}
+	cfg := defaultConfig(urls, "ns", nil, nil, "", "", "", "", false, false, commonconfig.HTTPClientConfig{})
+	if cfg.Targets[0].Name == cfg.Targets[1].Name {
+		t.Fatalf("expected the repeated URL's target to get a disambiguated name, got %q for both", cfg.Targets[0].Name)
+	}
+}
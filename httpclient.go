@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// httpClientConfigFromFlags builds the HTTPClientConfig used by the
+// legacy, flag-only scrape mode from its dedicated CLI flags, mirroring
+// the auth/tls options available per-target in the YAML config.
+func httpClientConfigFromFlags(unsecure bool, basicAuthUsername, basicAuthPassword, basicAuthPasswordFile, bearerToken, bearerTokenFile, proxyURL, tlsCAFile, tlsCertFile, tlsKeyFile, tlsServerName string) (commonconfig.HTTPClientConfig, error) {
+	cfg := commonconfig.HTTPClientConfig{
+		TLSConfig: commonconfig.TLSConfig{
+			InsecureSkipVerify: unsecure,
+			CAFile:             tlsCAFile,
+			CertFile:           tlsCertFile,
+			KeyFile:            tlsKeyFile,
+			ServerName:         tlsServerName,
+		},
+	}
+
+	if basicAuthUsername != "" {
+		cfg.BasicAuth = &commonconfig.BasicAuth{
+			Username:     basicAuthUsername,
+			Password:     commonconfig.Secret(basicAuthPassword),
+			PasswordFile: basicAuthPasswordFile,
+		}
+	}
+
+	if bearerToken != "" {
+		cfg.BearerToken = commonconfig.Secret(bearerToken)
+	}
+	if bearerTokenFile != "" {
+		cfg.BearerTokenFile = bearerTokenFile
+	}
+
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid --proxy.url: %s", err)
+		}
+		cfg.ProxyConfig.ProxyURL = commonconfig.URL{URL: u}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid http client flags: %s", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientConfigFromFlagsBasicAuth(t *testing.T) {
+	cfg, err := httpClientConfigFromFlags(false, "user", "pass", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.BasicAuth == nil {
+		t.Fatal("expected BasicAuth to be set")
+	}
+	if cfg.BasicAuth.Username != "user" || string(cfg.BasicAuth.Password) != "pass" {
+		t.Errorf("unexpected BasicAuth: %+v", cfg.BasicAuth)
+	}
+}
+
+func TestHTTPClientConfigFromFlagsBearerToken(t *testing.T) {
+	cfg, err := httpClientConfigFromFlags(false, "", "", "", "mytoken", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Validate() canonicalizes bearer_token into an Authorization header.
+	if cfg.Authorization == nil || cfg.Authorization.Type != "Bearer" || string(cfg.Authorization.Credentials) != "mytoken" {
+		t.Errorf("expected bearer token to be set as a Bearer Authorization, got %+v", cfg.Authorization)
+	}
+}
+
+func TestHTTPClientConfigFromFlagsTLS(t *testing.T) {
+	cfg, err := httpClientConfigFromFlags(true, "", "", "", "", "", "", "ca.pem", "cert.pem", "key.pem", "myserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !cfg.TLSConfig.InsecureSkipVerify {
+		t.Error("expected InsecureSkipVerify to mirror --unsecured")
+	}
+	if cfg.TLSConfig.CAFile != "ca.pem" || cfg.TLSConfig.CertFile != "cert.pem" || cfg.TLSConfig.KeyFile != "key.pem" || cfg.TLSConfig.ServerName != "myserver" {
+		t.Errorf("unexpected TLSConfig: %+v", cfg.TLSConfig)
+	}
+}
+
+func TestHTTPClientConfigFromFlagsInvalidProxyURL(t *testing.T) {
+	if _, err := httpClientConfigFromFlags(false, "", "", "", "", "", "://not-a-url", "", "", "", ""); err == nil {
+		t.Error("expected an error for an invalid --proxy.url")
+	}
+}
+
+func TestHTTPClientConfigFromFlagsRejectsBasicAuthAndBearerTogether(t *testing.T) {
+	if _, err := httpClientConfigFromFlags(false, "user", "pass", "", "mytoken", "", "", "", "", "", ""); err == nil {
+		t.Error("expected Validate() to reject combining basic auth with a bearer token")
+	}
+}
+
+func TestNewTargetCollectorSendsConfiguredBasicAuth(t *testing.T) {
+	var gotUser, gotPass string
+	var gotOK bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, gotOK = r.BasicAuth()
+		fmt.Fprint(w, `{"metric": 1}`)
+	}))
+	defer ts.Close()
+
+	httpClientConfig, err := httpClientConfigFromFlags(false, "user", "pass", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("httpClientConfigFromFlags: unexpected error: %s", err)
+	}
+
+	cfg := &TargetConfig{
+		Name:             "t",
+		URL:              ts.URL,
+		ValueLabels:      "v:^metric$",
+		HTTPClientConfig: httpClientConfig,
+	}
+
+	if _, err := newTargetCollector(cfg, 5*time.Second, false); err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	if !gotOK || gotUser != "user" || gotPass != "pass" {
+		t.Errorf("expected the scrape request to carry basic auth user/pass, got ok=%v user=%q pass=%q", gotOK, gotUser, gotPass)
+	}
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"regexp"
@@ -16,6 +15,8 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	commonconfig "github.com/prometheus/common/config"
 )
 
 const (
@@ -37,25 +38,181 @@ func regexStr2Map(regexString string) *map[string]*regexp.Regexp {
 	return &regexMap
 }
 
-// Exporter collects Elasticsearch stats from the given server and exports
-// them using the prometheus metrics package.
+// Exporter is a multiplexer over one or more configured scrape targets.
+// It implements prometheus.Collector by delegating Describe/Collect to
+// each target's own targetCollector.
 type Exporter struct {
-	Urls        []string
-	namespace   string
-	labels      []string
-	labelvalues []string
-	mutex       sync.RWMutex
-	debug       bool
-	jmx         bool
-	lowercase   bool
+	mutex sync.RWMutex
+
+	targets     []*targetCollector
+	concurrency int
+
 	nextrefresh time.Time
 	interval    time.Duration
 
-	up prometheus.Gauge
+	// Self-monitoring metrics about the scrape of each target's URL
+	// itself, mirroring blackbox_exporter's probe_* pattern - separate
+	// from each target's own json_up gauge and user-facing metrics.
+	scrapeUp       *prometheus.GaugeVec
+	scrapeDuration *prometheus.GaugeVec
+	scrapeBytes    *prometheus.GaugeVec
+	scrapeErrors   *prometheus.CounterVec
+}
+
+// NewExporter builds an Exporter that scrapes every target in cfg on the
+// given refresh interval, with at most concurrency targets being scraped
+// at once.
+func NewExporter(cfg *Config, timeout time.Duration, debug bool, refreshinterval time.Duration, concurrency int) (*Exporter, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	e := &Exporter{
+		nextrefresh: time.Now(),
+		interval:    refreshinterval,
+		concurrency: concurrency,
+
+		scrapeUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "json", Subsystem: "scrape",
+			Name: "up",
+			Help: "Was the last scrape of this target's URL itself successful?",
+		}, []string{"url"}),
+		scrapeDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "json", Subsystem: "scrape",
+			Name: "duration_seconds",
+			Help: "How long the last scrape of this target's URL took, in seconds.",
+		}, []string{"url"}),
+		scrapeBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "json", Subsystem: "scrape",
+			Name: "response_bytes",
+			Help: "Size of the last response body read from this target's URL.",
+		}, []string{"url"}),
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "json", Subsystem: "scrape",
+			Name: "errors_total",
+			Help: "Total number of failed scrapes of this target's URL, by reason.",
+		}, []string{"url", "reason"}),
+	}
+	for _, target := range cfg.Targets {
+		// A URL-less target is only meaningful to runOnce's
+		// --once --input.file path, which reads its payload from a
+		// file/stdin once and exits. A long-running Exporter has no
+		// input file to fall back on, so it would just fail to scrape
+		// an empty URL on every refresh forever.
+		if target.URL == "" {
+			return nil, fmt.Errorf("target %q has no url, which is only valid with --once --input.file", target.Name)
+		}
+		t, err := newTargetCollector(target, timeout, debug)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %s", target.Name, err)
+		}
+		e.targets = append(e.targets, t)
+	}
+	return e, nil
+}
+
+// Describe describes all the metrics ever exported by every configured
+// target. It implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, t := range e.targets {
+		t.describe(ch)
+	}
+	e.scrapeUp.Describe(ch)
+	e.scrapeDuration.Describe(ch)
+	e.scrapeBytes.Describe(ch)
+	e.scrapeErrors.Describe(ch)
+}
+
+// Collect fetches the stats from every configured target and delivers
+// them as Prometheus metrics. It implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mutex.Lock() // To protect metrics from concurrent collects.
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+	if e.nextrefresh.Before(now) {
+		e.refreshAll()
+		e.nextrefresh = now.Add(e.interval)
+	}
+
+	for _, t := range e.targets {
+		t.collect(ch)
+	}
+	e.scrapeUp.Collect(ch)
+	e.scrapeDuration.Collect(ch)
+	e.scrapeBytes.Collect(ch)
+	e.scrapeErrors.Collect(ch)
+}
+
+// scrapeResult is one target's refresh() outcome, collected by refreshAll
+// so that the json_scrape_* vectors can be updated serially afterwards
+// instead of from several goroutines at once.
+type scrapeResult struct {
+	url      string
+	bytes    int
+	duration time.Duration
+	reason   string
+	err      error
+}
+
+// refreshAll scrapes every target concurrently, bounded by e.concurrency,
+// so one slow or unreachable URL no longer delays (or blanks out) the
+// others' results the way a serial loop would.
+func (e *Exporter) refreshAll() {
+	sem := make(chan struct{}, e.concurrency)
+	var wg sync.WaitGroup
+	results := make([]scrapeResult, len(e.targets))
+
+	for i, t := range e.targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t *targetCollector) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			bytes, reason, err := t.refresh()
+			results[i] = scrapeResult{
+				url:      t.cfg.URL,
+				bytes:    bytes,
+				duration: time.Since(start),
+				reason:   reason,
+				err:      err,
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		e.scrapeDuration.WithLabelValues(r.url).Set(r.duration.Seconds())
+		e.scrapeBytes.WithLabelValues(r.url).Set(float64(r.bytes))
+		if r.err != nil {
+			e.scrapeErrors.WithLabelValues(r.url, r.reason).Inc()
+		}
+		// json_scrape_up tracks reaching the URL itself, same as
+		// blackbox_exporter's probe_success - a parse_error means the
+		// scrape succeeded but the payload was bad, which json_up (not
+		// this metric) already reports.
+		if r.reason == "connect_error" || r.reason == "read_error" {
+			e.scrapeUp.WithLabelValues(r.url).Set(0)
+		} else {
+			e.scrapeUp.WithLabelValues(r.url).Set(1)
+		}
+	}
+}
+
+// targetCollector holds the per-target state that used to live directly
+// on Exporter: its own label set, metric families, blacklist/whitelist,
+// path/value label regexes and HTTP client.
+type targetCollector struct {
+	cfg *TargetConfig
+
+	labels      []string
+	labelvalues []string
 
-	gauges  map[string]*prometheus.GaugeVec
-	updated map[string]uint
-	exist   map[string]uint
+	families map[string]*metricFamily
+
+	up prometheus.Gauge
 
 	blacklist *regexp.Regexp
 	whitelist *regexp.Regexp
@@ -64,94 +221,151 @@ type Exporter struct {
 
 	pathlabels map[string]*regexp.Regexp
 
+	debug bool
+
 	client *http.Client
 }
 
-// JSONExporter returns an initialized Exporter.
-func JSONExporter(urls []string, timeout time.Duration, namespace string, labels []string, labelvalues []string, debug bool, unsecure bool, blacklist string, whitelist string, refreshinterval time.Duration, pathlabels string, valuelabels string, jmx bool, lowercase bool) *Exporter {
-	gauges := make(map[string]*prometheus.GaugeVec)
-	updated := make(map[string]uint)
-	exist := make(map[string]uint)
-	var blist, wlist *regexp.Regexp
+// newTargetCollector returns an initialized targetCollector for cfg.
+func newTargetCollector(cfg *TargetConfig, timeout time.Duration, debug bool) (*targetCollector, error) {
 	var IleagalCharsConversion = []string{" ", "_", ",", "_", ":", "_", "-", "_", "=", "_", ".", "_"}
-	if blacklist != "" {
-		blist = regexp.MustCompile(blacklist)
+	var blist, wlist *regexp.Regexp
+	if cfg.Blacklist != "" {
+		blist = regexp.MustCompile(cfg.Blacklist)
 	}
-	if whitelist != "" {
-		wlist = regexp.MustCompile(whitelist)
+	if cfg.Whitelist != "" {
+		wlist = regexp.MustCompile(cfg.Whitelist)
 	}
 
-	// Init our exporter.
-	exporter := Exporter{
-		Urls:        urls,
-		namespace:   namespace,
+	labels := make([]string, 0, len(cfg.Labels))
+	labelvalues := make([]string, 0, len(cfg.Labels))
+	for k, v := range cfg.Labels {
+		labels = append(labels, k)
+		labelvalues = append(labelvalues, v)
+	}
+
+	client, err := commonconfig.NewClientFromConfig(cfg.HTTPClientConfig, cfg.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http client: %s", err)
+	}
+	client.Timeout = timeout
+
+	t := &targetCollector{
+		cfg: cfg,
+
 		labels:      labels,
 		labelvalues: labelvalues,
-		debug:       debug,
-		jmx:         jmx,
-		lowercase:   lowercase,
-		nextrefresh: time.Now(),
-		interval:    refreshinterval,
+
+		families: make(map[string]*metricFamily),
 
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Name:      "up",
-			Help:      "Was the json query successful?",
+			Namespace:   cfg.Namespace,
+			Name:        "up",
+			ConstLabels: prometheus.Labels{"target": cfg.Name},
+			Help:        "Was the last json query for this target successful?",
 		}),
 
-		gauges:  gauges,
-		updated: updated,
-		exist:   exist,
-
 		blacklist: blist,
 		whitelist: wlist,
 
 		cleaner: strings.NewReplacer(IleagalCharsConversion...),
 
-		pathlabels: *(regexStr2Map(pathlabels)),
+		pathlabels: *(regexStr2Map(cfg.PathLabels)),
 
-		client: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: unsecure},
-				Dial: func(netw, addr string) (net.Conn, error) {
-					c, err := net.DialTimeout(netw, addr, timeout)
-					if err != nil {
-						return nil, err
-					}
-					if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
-						return nil, err
-					}
-					return c, nil
-				},
-			},
-		},
+		debug: debug,
+
+		client: client,
 	}
 
-	exporter.collectLabels(regexStr2Map(valuelabels))
+	t.collectLabels(regexStr2Map(cfg.ValueLabels))
 
-	return &exporter
+	return t, nil
 }
 
-// Describe describes all the metrics ever exported by the elasticsearch
-// exporter. It implements prometheus.Collector.
-func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- e.up.Desc()
+// describe sends the Desc of every metric this target has ever produced.
+func (t *targetCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- t.up.Desc()
+	for _, f := range t.families {
+		f.collector().Describe(ch)
+	}
+}
+
+// collect sends the current value of every metric this target holds.
+func (t *targetCollector) collect(ch chan<- prometheus.Metric) {
+	ch <- t.up
+	for _, f := range t.families {
+		f.collector().Collect(ch)
+	}
+}
+
+// refresh scrapes the target's URL, re-extracts its metrics, and then
+// expires any series whose mapping TTL has elapsed since it was last seen.
+// It returns the number of response bytes read and, on failure, a short
+// machine-readable reason (suitable for a json_scrape_errors_total label)
+// alongside the error.
+func (t *targetCollector) refresh() (int, string, error) {
+	body, reason, err := t.fetch()
+	if err != nil {
+		t.up.Set(0)
+		log.Println("Error while querying Json endpoint:", t.cfg.URL, err)
+		return 0, reason, err
+	}
+
+	if err := t.ingest(body); err != nil {
+		log.Println("Failed to unmarshal JSON into struct:", err)
+		return len(body), "parse_error", err
+	}
+
+	now := time.Now()
+	for _, f := range t.families {
+		f.sweep(now)
+	}
+	return len(body), "", nil
+}
+
+// fetch retrieves the target's URL and returns its raw response body. On
+// error it also returns a short reason ("connect_error" or "read_error")
+// identifying which step failed.
+func (t *targetCollector) fetch() ([]byte, string, error) {
+	resp, err := t.client.Get(t.cfg.URL)
+	if err != nil {
+		return nil, "connect_error", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "read_error", fmt.Errorf("failed to read response body: %s", err)
+	}
+	return body, "", nil
+}
+
+// ingest parses body as JSON and extracts its metrics, the same way a
+// scrape does - used both by refresh and by the --once / push-mode path,
+// which feeds in a payload read from a file or stdin instead.
+func (t *targetCollector) ingest(body []byte) error {
+	var allStats map[string]interface{}
+	if err := json.Unmarshal(body, &allStats); err != nil {
+		t.up.Set(0)
+		return err
+	}
 
-	for _, g := range e.gauges {
-		g.Describe(ch)
+	t.up.Set(1)
+	if !t.extractJSONPathMetrics(allStats) {
+		t.extractJSON("", allStats)
 	}
+	return nil
 }
 
 // Matching metric names against blacklist/whitelist
-func (e *Exporter) matchMetric(name string) bool {
-	if (e.blacklist != nil && e.blacklist.MatchString(name)) || (e.whitelist != nil && !e.whitelist.MatchString(name)) {
+func (t *targetCollector) matchMetric(name string) bool {
+	if (t.blacklist != nil && t.blacklist.MatchString(name)) || (t.whitelist != nil && !t.whitelist.MatchString(name)) {
 		return false
 	}
 	return true
 }
 
 // Match metric name based on regex list - for usage as label value
-func (e *Exporter) matchLabel(name string, labelRegex *map[string]*regexp.Regexp) string {
+func (t *targetCollector) matchLabel(name string, labelRegex *map[string]*regexp.Regexp) string {
 	for k, v := range *labelRegex {
 		if v.MatchString(name) {
 			return k
@@ -161,7 +375,7 @@ func (e *Exporter) matchLabel(name string, labelRegex *map[string]*regexp.Regexp
 }
 
 // Match metric name based on regex list - for usage as label value
-func (e *Exporter) matchLabels(name string, labelRegex *map[string]*regexp.Regexp) []string {
+func (t *targetCollector) matchLabels(name string, labelRegex *map[string]*regexp.Regexp) []string {
 	var labels []string
 	for k, v := range *labelRegex {
 		if v.MatchString(name) {
@@ -171,43 +385,77 @@ func (e *Exporter) matchLabels(name string, labelRegex *map[string]*regexp.Regex
 	return labels
 }
 
-// Adding single gauge metric to the slice
-func (e *Exporter) addGauge(name string, value float64, help string) {
-	if e.lowercase {
+// addMetric records value under name, picking the metric family's type,
+// buckets and TTL from the first mapping that matches (or the target's
+// defaults when none do).
+func (t *targetCollector) addMetric(name string, value float64, help string) {
+	if t.cfg.Lowercase {
 		name = strings.ToLower(name)
 	}
-	if e.matchMetric(name) {
-		if _, exists := e.gauges[name]; !exists {
-			e.gauges[name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: e.namespace, Name: name, Help: help}, e.labels)
-			e.updated[name] = 0
-			e.exist[name] = 0
+	if !t.matchMetric(name) {
+		return
+	}
+
+	finalName := name
+	mtype := t.cfg.Defaults.Type
+	ttl := t.cfg.Defaults.TTL
+	buckets := t.cfg.Defaults.Buckets
+
+	if mapping := t.cfg.lookupMapping(name); mapping != nil {
+		if mapping.Name != "" {
+			finalName = mapping.Name
+		}
+		if mapping.Type != "" {
+			mtype = mapping.Type
+		}
+		if mapping.TTL != 0 {
+			ttl = mapping.TTL
+		}
+		if mapping.Buckets != nil {
+			buckets = mapping.Buckets
 		}
-		e.gauges[name].WithLabelValues(e.labelvalues...).Set(value)
-		e.updated[name]++
 	}
+
+	t.record(finalName, value, help, mtype, buckets, ttl)
+}
+
+// record looks up (or creates) the metric family named name and observes
+// value under the target's current label set. mtype defaults to gauge
+// when unset.
+func (t *targetCollector) record(name string, value float64, help string, mtype MetricType, buckets []float64, ttl time.Duration) {
+	if mtype == "" {
+		mtype = MetricTypeGauge
+	}
+
+	fam, exists := t.families[name]
+	if !exists {
+		fam = newMetricFamily(t.cfg.Namespace, name, help, mtype, buckets, t.labels)
+		t.families[name] = fam
+	}
+	fam.observe(t.labelvalues, value, ttl)
 }
 
 // Adding a label to slices
-func (e *Exporter) addLabel(name string, value string) {
-	if e.lowercase {
+func (t *targetCollector) addLabel(name string, value string) {
+	if t.cfg.Lowercase {
 		name = strings.ToLower(name)
 	}
-	e.labels = append(e.labels, name)
-	e.labelvalues = append(e.labelvalues, value)
+	t.labels = append(t.labels, name)
+	t.labelvalues = append(t.labelvalues, value)
 }
 
 // Delete the latest label
-func (e *Exporter) delLastLabels(num int) {
-	newLastIndex := len(e.labels) - num
+func (t *targetCollector) delLastLabels(num int) {
+	newLastIndex := len(t.labels) - num
 	if newLastIndex >= 0 {
-		e.labels = e.labels[:newLastIndex]
-		e.labelvalues = e.labelvalues[:newLastIndex]
+		t.labels = t.labels[:newLastIndex]
+		t.labelvalues = t.labelvalues[:newLastIndex]
 	}
 }
 
 // Extract Labels from generic json interface
 // push extracted labels to all metrics
-func (e *Exporter) extractLabel(metric string, jsonInt map[string]interface{}, regexMap *map[string]*regexp.Regexp) {
+func (t *targetCollector) extractLabel(metric string, jsonInt map[string]interface{}, regexMap *map[string]*regexp.Regexp) {
 	newMetric := ""
 	for k, v := range jsonInt {
 		if len(*regexMap) == 0 {
@@ -218,85 +466,85 @@ func (e *Exporter) extractLabel(metric string, jsonInt map[string]interface{}, r
 		} else {
 			newMetric = k
 		}
-		label := e.matchLabel(newMetric, regexMap)
+		label := t.matchLabel(newMetric, regexMap)
 		if label != "" {
 			delete(*regexMap, label)
-			if e.debug {
+			if t.debug {
 				log.Println("Value label regex match with:", newMetric)
 			}
 			switch vv := v.(type) {
 			case string:
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is string", vv)
 				}
-				e.addLabel(label, vv)
+				t.addLabel(label, vv)
 			case int:
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is int =>", vv)
 				}
-				e.addLabel(label, strconv.Itoa(vv))
+				t.addLabel(label, strconv.Itoa(vv))
 			case float64:
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is float64 =>", vv)
 				}
-				e.addLabel(label, strconv.FormatFloat(vv, 'E', -1, 64))
+				t.addLabel(label, strconv.FormatFloat(vv, 'E', -1, 64))
 			case bool:
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is bool =>", vv)
 				}
-				e.addLabel(label, strconv.FormatBool(vv))
+				t.addLabel(label, strconv.FormatBool(vv))
 			}
 		} else {
 			switch vv := v.(type) {
 			case map[string]interface{}:
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is hash")
 				}
-				e.extractLabel(newMetric, vv, regexMap)
+				t.extractLabel(newMetric, vv, regexMap)
 			}
 		}
 	}
 }
 
-// Collect labels from all URLs based on label regex list from JSON URL's
-func (e *Exporter) collectLabels(regexMap *map[string]*regexp.Regexp) {
-	for _, URI := range e.Urls {
-		resp, err := e.client.Get(URI)
-		if err != nil {
-			log.Println("Error while querying Json endpoint:", err)
-			continue
-		}
+// Collect labels from the target's URL based on the value label regex list
+func (t *targetCollector) collectLabels(regexMap *map[string]*regexp.Regexp) {
+	if len(*regexMap) == 0 {
+		return
+	}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Println("Failed to read Json response body:", err)
-			resp.Body.Close()
-			continue
-		}
+	resp, err := t.client.Get(t.cfg.URL)
+	if err != nil {
+		log.Println("Error while querying Json endpoint:", err)
+		return
+	}
 
-		var allJSON map[string]interface{}
-		err = json.Unmarshal(body, &allJSON)
-		if err != nil {
-			log.Println("Failed to unmarshal JSON into struct:", err)
-			continue
-		}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Failed to read Json response body:", err)
+		resp.Body.Close()
+		return
+	}
+	resp.Body.Close()
 
-		// Extracrt the metrics from the json interface
-		e.extractLabel("", allJSON, regexMap)
-		if len(*regexMap) == 0 {
-			break
-		}
+	var allJSON map[string]interface{}
+	if err := json.Unmarshal(body, &allJSON); err != nil {
+		log.Println("Failed to unmarshal JSON into struct:", err)
+		return
 	}
+
+	// Extracrt the metrics from the json interface
+	t.extractLabel("", allJSON, regexMap)
 }
 
 // Extract metrics of generic json interface
-// push extracted metrics accordingly (to guages only at the moment)
-func (e *Exporter) extractJSON(metric string, jsonInt map[string]interface{}) {
+// push extracted metrics accordingly (to gauges, counters or histograms,
+// depending on the target's mapping config)
+func (t *targetCollector) extractJSON(metric string, jsonInt map[string]interface{}) {
 	newMetric := ""
 	//Handle jmx mode metric name replacement
-	if e.jmx {
+	if t.cfg.JMX {
 		if name, ok := jsonInt["name"].(string); ok {
-			metric = e.cleaner.Replace(name)
+			metric = t.cleaner.Replace(name)
 		}
 	}
 	for k, v := range jsonInt {
@@ -305,20 +553,20 @@ func (e *Exporter) extractJSON(metric string, jsonInt map[string]interface{}) {
 		} else {
 			newMetric = k
 		}
-		labels := e.matchLabels(newMetric, &e.pathlabels)
+		labels := t.matchLabels(newMetric, &t.pathlabels)
 		for _, label := range labels {
-			value := e.pathlabels[label].FindStringSubmatch(newMetric)
+			value := t.pathlabels[label].FindStringSubmatch(newMetric)
 			if len(value) > 1 {
 				newMetric = strings.Replace(newMetric, value[0], "", -1)
 				if len(newMetric) < 1 {
 					newMetric = label
 				}
-				e.addLabel(label, value[1])
+				t.addLabel(label, value[1])
 			}
 		}
 		switch vv := v.(type) {
 		case string:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is string", vv)
 			}
 			//Handle the case where the string contains json value
@@ -328,58 +576,58 @@ func (e *Exporter) extractJSON(metric string, jsonInt map[string]interface{}) {
 				if err != nil {
 					log.Println("Failed to parse json from string", newMetric)
 				} else {
-					if e.debug {
+					if t.debug {
 						log.Println("Extracting json values from the string in:", newMetric)
 					}
-					e.extractJSON(newMetric, stats)
+					t.extractJSON(newMetric, stats)
 				}
 			}
 		case int:
-			if e.debug {
-				log.Println(newMetric, "is int =>", vv, e.labels)
+			if t.debug {
+				log.Println(newMetric, "is int =>", vv, t.labels)
 			}
-			e.addGauge(newMetric, float64(vv), newMetric+helpSuffix)
+			t.addMetric(newMetric, float64(vv), newMetric+helpSuffix)
 		case float64:
-			if e.debug {
-				log.Println(newMetric, "is float64 =>", vv, e.labels)
+			if t.debug {
+				log.Println(newMetric, "is float64 =>", vv, t.labels)
 			}
-			e.addGauge(newMetric, vv, newMetric+helpSuffix)
+			t.addMetric(newMetric, vv, newMetric+helpSuffix)
 		case bool:
 			if vv {
-				if e.debug {
-					log.Println(newMetric, "is bool => 1", e.labels)
+				if t.debug {
+					log.Println(newMetric, "is bool => 1", t.labels)
 				}
-				e.addGauge(newMetric, float64(1), newMetric+helpSuffix)
+				t.addMetric(newMetric, float64(1), newMetric+helpSuffix)
 			} else {
-				if e.debug {
-					log.Println(newMetric, "is bool => 0", e.labels)
+				if t.debug {
+					log.Println(newMetric, "is bool => 0", t.labels)
 				}
-				e.addGauge(newMetric, float64(0), newMetric+helpSuffix)
+				t.addMetric(newMetric, float64(0), newMetric+helpSuffix)
 			}
 		case map[string]interface{}:
-			if e.debug {
-				log.Println(newMetric, "is hash", e.labels)
+			if t.debug {
+				log.Println(newMetric, "is hash", t.labels)
 			}
-			e.extractJSON(newMetric, vv)
+			t.extractJSON(newMetric, vv)
 		case []interface{}:
-			if e.debug {
-				log.Println(newMetric, "is an array", e.labels)
+			if t.debug {
+				log.Println(newMetric, "is an array", t.labels)
 			}
-			e.extractJSONArray(newMetric, vv)
+			t.extractJSONArray(newMetric, vv)
 		default:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is of a type I don't know how to handle")
 			}
 		}
 		// Remove path labels that were added for this JSON subtree only
 		if len(labels) > 0 {
-			e.delLastLabels(len(labels))
+			t.delLastLabels(len(labels))
 		}
 	}
 }
 
 // Extract metrics from json array interface
-func (e *Exporter) extractJSONArray(metric string, jsonInt []interface{}) {
+func (t *targetCollector) extractJSONArray(metric string, jsonInt []interface{}) {
 	newMetric := ""
 	for k, v := range jsonInt {
 		if len(metric) > 0 {
@@ -387,20 +635,20 @@ func (e *Exporter) extractJSONArray(metric string, jsonInt []interface{}) {
 		} else {
 			newMetric = strconv.Itoa(k)
 		}
-		labels := e.matchLabels(newMetric, &e.pathlabels)
+		labels := t.matchLabels(newMetric, &t.pathlabels)
 		for _, label := range labels {
-			value := e.pathlabels[label].FindStringSubmatch(newMetric)
+			value := t.pathlabels[label].FindStringSubmatch(newMetric)
 			if len(value) > 1 {
 				newMetric = strings.Replace(newMetric, value[0], "", -1)
 				if len(newMetric) < 1 {
 					newMetric = label
 				}
-				e.addLabel(label, value[1])
+				t.addLabel(label, value[1])
 			}
 		}
 		switch vv := v.(type) {
 		case string:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is string", vv)
 			}
 			if len(vv) > 2 && vv[0] == '{' {
@@ -409,112 +657,52 @@ func (e *Exporter) extractJSONArray(metric string, jsonInt []interface{}) {
 				if err != nil {
 					log.Println("Failed to parse json from string", newMetric)
 				} else {
-					e.extractJSON(newMetric, stats)
-					if e.debug {
+					t.extractJSON(newMetric, stats)
+					if t.debug {
 						log.Println("Extracting json values from the string in:", newMetric)
 					}
 				}
 			}
 		case int:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is int =>", vv)
 			}
-			e.addGauge(newMetric, float64(vv), newMetric+helpSuffix)
+			t.addMetric(newMetric, float64(vv), newMetric+helpSuffix)
 		case float64:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is int =>", vv)
 			}
-			e.addGauge(newMetric, vv, newMetric+helpSuffix)
+			t.addMetric(newMetric, vv, newMetric+helpSuffix)
 		case bool:
 			if vv {
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is bool => 1")
 				}
-				e.addGauge(newMetric, float64(1), newMetric+helpSuffix)
+				t.addMetric(newMetric, float64(1), newMetric+helpSuffix)
 			} else {
-				if e.debug {
+				if t.debug {
 					log.Println(newMetric, "is bool => 0")
 				}
-				e.addGauge(newMetric, float64(0), newMetric+helpSuffix)
+				t.addMetric(newMetric, float64(0), newMetric+helpSuffix)
 			}
 		case map[string]interface{}:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is hash")
 			}
-			e.extractJSON(newMetric, vv)
+			t.extractJSON(newMetric, vv)
 		case []interface{}:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is an array")
 			}
-			e.extractJSONArray(newMetric, vv)
+			t.extractJSONArray(newMetric, vv)
 		default:
-			if e.debug {
+			if t.debug {
 				log.Println(newMetric, "is of a type I don't know how to handle")
 			}
 		}
 		if len(labels) > 0 {
-			e.delLastLabels(len(labels))
-		}
-	}
-}
-
-// Collect fetches the stats from configured elasticsearch location and
-// delivers them as Prometheus metrics. It implements prometheus.Collector.
-func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	e.mutex.Lock() // To protect metrics from concurrent collects.
-	defer e.mutex.Unlock()
-
-	defer func() { ch <- e.up }()
-
-	if e.nextrefresh.Before(time.Now()) {
-		for name, updated := range e.updated {
-			if updated < e.exist[name] {
-				//delete updated value
-				delete(e.updated, name)
-				delete(e.exist, name)
-				//delete metricvec
-				delete(e.gauges, name)
-			} else {
-				e.exist[name] = e.updated[name]
-				//reset value
-				e.updated[name] = 0
-			}
+			t.delLastLabels(len(labels))
 		}
-
-		for _, URI := range e.Urls {
-			resp, err := e.client.Get(URI)
-			if err != nil {
-				e.up.Set(0)
-				log.Println("Error while querying Json endpoint:", err)
-				continue
-			}
-
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				log.Println("Failed to read Json response body:", err)
-				e.up.Set(0)
-				continue
-			}
-			resp.Body.Close()
-
-			e.up.Set(1)
-
-			var allStats map[string]interface{}
-			err = json.Unmarshal(body, &allStats)
-			if err != nil {
-				log.Println("Failed to unmarshal JSON into struct:", err)
-				continue
-			}
-
-			// Extracrt the metrics from the json interface
-			e.extractJSON("", allStats)
-		}
-		e.nextrefresh = time.Now().Add(e.interval)
-	}
-	// Report metrics.
-
-	for _, g := range e.gauges {
-		g.Collect(ch)
 	}
 }
 
@@ -523,53 +711,110 @@ func main() {
 		version       = flag.Bool("version", false, "Print version information.")
 		listenAddress = flag.String("web.listen-address", ":9109", "Address to listen on for web interface and telemetry.")
 		metricsPath   = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+		configFile    = flag.String("config.file", "", "Path to a YAML file configuring one or more scrape targets. When unset, the legacy flags below describe a single synthesized target per URL.")
 		Labels        = flag.String("labels", "", "List of labels (comma seperated).")
 		LabelValues   = flag.String("values", "", "List of label values (comma seperated)")
 		Timeout       = flag.Duration("timeout", 5*time.Second, "Timeout for trying to get to json URI.")
 		interval      = flag.Duration("interval", 1*time.Minute, "Refresh interval for json scraping.")
+		concurrency   = flag.Int("concurrency", 10, "Maximum number of targets to scrape at once.")
 		namespace     = flag.String("namespace", "json", "Namespace for metrics exported from Json.")
 		debug         = flag.Bool("debug", false, "Print debug information")
 		lowercase     = flag.Bool("lowercase", true, "Lowercase metric names")
 		jmx           = flag.Bool("jmx", false, "Enable jmx mode when parsing - name attribute will turn into path")
-		unsecured     = flag.Bool("unsecured", false, "Accept untrusted https certificate(used for private certificates)")
+		unsecured     = flag.Bool("unsecured", false, "Accept untrusted https certificate(used for private certificates). Alias for --tls.insecure-skip-verify.")
 		blacklist     = flag.String("blacklist", "", "Blacklist regex expression of metric names.")
 		whitelist     = flag.String("whitelist", "", "Whitelist regex expression of metric names.")
 		valuelabel    = flag.String("valuelabel", "", "Create labels from values using metric-name regex, format: <label1>:<regex1>[/<label2>:<regex2>[/...]].")
 		pathlabel     = flag.String("pathlabel", "", "Create labels from path segments with regex match, format: <label1>:<regex1>[/<label2>:<regex2>[/...]].")
+
+		basicAuthUsername     = flag.String("basic-auth.username", "", "Username for HTTP basic authentication against the json URI.")
+		basicAuthPassword     = flag.String("basic-auth.password", "", "Password for HTTP basic authentication against the json URI.")
+		basicAuthPasswordFile = flag.String("basic-auth.password-file", "", "File containing the password for HTTP basic authentication; reloaded on every scrape.")
+		bearerToken           = flag.String("bearer-token", "", "Bearer token for authenticating against the json URI.")
+		bearerTokenFile       = flag.String("bearer-token-file", "", "File containing the bearer token; reloaded on every scrape.")
+		proxyURL              = flag.String("proxy.url", "", "HTTP proxy to use when scraping the json URI.")
+		tlsCAFile             = flag.String("tls.ca-file", "", "CA certificate file to validate the json URI's certificate against.")
+		tlsCertFile           = flag.String("tls.cert-file", "", "Client certificate file for mTLS against the json URI.")
+		tlsKeyFile            = flag.String("tls.key-file", "", "Client key file for mTLS against the json URI.")
+		tlsServerName         = flag.String("tls.server-name", "", "Server name to verify the json URI's certificate against.")
+
+		once         = flag.Bool("once", false, "Scrape (or read --input.file) exactly once, push the result to --push.gateway and exit, instead of starting the HTTP listener.")
+		inputFile    = flag.String("input.file", "", "With --once, read the JSON payload from this file instead of scraping the target's URL. Use - for stdin.")
+		pushGateway  = flag.String("push.gateway", "", "Pushgateway URL to push to when --once is set.")
+		pushJob      = flag.String("push.job", "json_exporter", "job label to push metrics under when --once is set.")
+		pushGrouping = flag.String("push.grouping", "", "Additional grouping key for the push, format: <label1>=<value1>[,<label2>=<value2>[,...]].")
 	)
 	flag.Parse()
 	log.Println("json_exporter", Version)
 	if *version {
 		return
 	}
-	urls := flag.Args()
-	if len(urls) < 1 {
-		log.Fatal("Got no URL's, please add use the following syntax to add URL's: json_exporter [options] <URL1>[ <URL2>[ ..<URLn>]]")
+
+	var cfg *Config
+	if *configFile != "" {
+		var err error
+		cfg, err = LoadConfig(*configFile)
+		if err != nil {
+			log.Fatal("Failed to load config file:", err)
+		}
 	} else {
-		log.Println("Got the following Url list", urls)
-	}
-	//Importing static labels
-	labels := []string{}
-	labelValues := []string{}
-	if len(*Labels) > 0 && len(*LabelValues) > 0 {
-		labels = strings.Split(*Labels, ",")
-		labelValues = strings.Split(*LabelValues, ",")
-		if len(labels) != len(labelValues) {
-			log.Fatal("Labels amount does not match value amount!!!")
+		urls := flag.Args()
+		if len(urls) < 1 {
+			if !(*once && *inputFile != "") {
+				log.Fatal("Got no URL's, please add use the following syntax to add URL's: json_exporter [options] <URL1>[ <URL2>[ ..<URLn>]]")
+			}
+			// --once --input.file reads its payload from a file/stdin and
+			// never scrapes a URL, so there's nothing to require here.
+			urls = []string{""}
+		}
+
+		if len(urls) == 1 && urls[0] == "" {
+			log.Println("No URL given; reading payload from --input.file instead")
+		} else {
+			log.Println("Got the following Url list", urls)
+		}
+
+		labels := []string{}
+		labelValues := []string{}
+		if len(*Labels) > 0 && len(*LabelValues) > 0 {
+			labels = strings.Split(*Labels, ",")
+			labelValues = strings.Split(*LabelValues, ",")
+			if len(labels) != len(labelValues) {
+				log.Fatal("Labels amount does not match value amount!!!")
+			}
 		}
+
+		httpClientConfig, err := httpClientConfigFromFlags(*unsecured, *basicAuthUsername, *basicAuthPassword, *basicAuthPasswordFile, *bearerToken, *bearerTokenFile, *proxyURL, *tlsCAFile, *tlsCertFile, *tlsKeyFile, *tlsServerName)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		cfg = defaultConfig(urls, *namespace, labels, labelValues, *blacklist, *whitelist, *pathlabel, *valuelabel, *jmx, *lowercase, httpClientConfig)
 	}
 
-	exporter := JSONExporter(urls, *Timeout, *namespace, labels, labelValues, *debug, *unsecured, *blacklist, *whitelist, *interval, *pathlabel, *valuelabel, *jmx, *lowercase)
+	if *once {
+		if err := runOnce(cfg, *Timeout, *debug, *inputFile, *pushGateway, *pushJob, *pushGrouping); err != nil {
+			log.Fatal("Failed to push metrics:", err)
+		}
+		return
+	}
+
+	exporter, err := NewExporter(cfg, *Timeout, *debug, *interval, *concurrency)
+	if err != nil {
+		log.Fatal("Failed to build exporter:", err)
+	}
 	prometheus.MustRegister(exporter)
 
 	log.Println("Starting Server:", *listenAddress)
-	http.Handle(*metricsPath, prometheus.Handler())
+	http.Handle(*metricsPath, promhttp.Handler())
+	http.HandleFunc("/probe", newProbeHandler(cfg, *Timeout, *debug))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>JSON Exporter</title></head>
              <body>
              <h1>JSON Exporter</h1>
              <p><a href='` + *metricsPath + `'>Metrics</a></p>
+             <p><a href='/probe?target=http://example.com/metrics.json'>Probe</a></p>
              </body>
              </html>`))
 	})
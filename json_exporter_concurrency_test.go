@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// TestNewExporterRejectsURLLessTarget ensures a target with no url (only
+// meaningful to runOnce's --once --input.file path) can't be fed to a
+// long-running Exporter, where it would fail to scrape an empty URL on
+// every refresh forever instead of failing fast at startup.
+func TestNewExporterRejectsURLLessTarget(t *testing.T) {
+	cfg := &Config{Targets: []*TargetConfig{{Name: "no_url"}}}
+	if _, err := NewExporter(cfg, time.Second, false, time.Minute, 1); err == nil {
+		t.Error("expected NewExporter to reject a target with no url")
+	}
+}
+
+// TestRefreshAllScrapesTargetsConcurrently builds two slow targets and
+// asserts the combined wall time is close to one slow scrape, not their
+// sum - catching a regression back to a serial refresh loop.
+func TestRefreshAllScrapesTargetsConcurrently(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	slow := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			fmt.Fprint(w, `{"metric": 1}`)
+		}))
+	}
+	ts1, ts2 := slow(), slow()
+	defer ts1.Close()
+	defer ts2.Close()
+
+	cfg := defaultConfig([]string{ts1.URL, ts2.URL}, "concurrency_test", nil, nil, "", "", "", "", false, false, commonconfig.HTTPClientConfig{})
+
+	e, err := NewExporter(cfg, time.Second, false, time.Minute, 2)
+	if err != nil {
+		t.Fatalf("NewExporter: unexpected error: %s", err)
+	}
+
+	start := time.Now()
+	e.refreshAll()
+	elapsed := time.Since(start)
+
+	if elapsed >= delay*2 {
+		t.Errorf("refreshAll took %s, expected well under %s if targets were scraped concurrently", elapsed, delay*2)
+	}
+}
+
+// TestRefreshAllOneFailingTargetDoesNotBlankOthers asserts that a target
+// whose URL can't be reached only affects its own json_scrape_* series,
+// leaving a healthy target's up/duration/bytes intact.
+func TestRefreshAllOneFailingTargetDoesNotBlankOthers(t *testing.T) {
+	var hits int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, `{"metric": 1}`)
+	}))
+	defer ok.Close()
+
+	// A URL nothing is listening on - Get() fails with a connect error.
+	badURL := "http://127.0.0.1:1/"
+
+	cfg := defaultConfig([]string{ok.URL, badURL}, "concurrency_test", nil, nil, "", "", "", "", false, false, commonconfig.HTTPClientConfig{})
+	e, err := NewExporter(cfg, time.Second, false, time.Minute, 2)
+	if err != nil {
+		t.Fatalf("NewExporter: unexpected error: %s", err)
+	}
+
+	e.refreshAll()
+
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("expected the healthy target to be scraped exactly once, got %d", hits)
+	}
+	if got := testutil.ToFloat64(e.scrapeUp.WithLabelValues(ok.URL)); got != 1 {
+		t.Errorf("expected json_scrape_up for the healthy target to be 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(e.scrapeUp.WithLabelValues(badURL)); got != 0 {
+		t.Errorf("expected json_scrape_up for the failing target to be 0, got %v", got)
+	}
+}
+
+// TestExporterCollectExposesScrapeMetrics confirms the json_scrape_*
+// vectors show up in scraped output alongside each target's own metrics.
+func TestExporterCollectExposesScrapeMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"metric": 1}`)
+	}))
+	defer ts.Close()
+
+	cfg := defaultConfig([]string{ts.URL}, "concurrency_test", nil, nil, "", "", "", "", false, false, commonconfig.HTTPClientConfig{})
+	e, err := NewExporter(cfg, time.Second, false, time.Minute, 1)
+	if err != nil {
+		t.Fatalf("NewExporter: unexpected error: %s", err)
+	}
+
+	body := scrapeCollectorBody(t, e)
+	// json_scrape_errors_total isn't asserted here: a CounterVec exposes no
+	// series for a label combination until it's been incremented at least
+	// once, so a clean scrape never mentions it.
+	for _, want := range []string{"json_scrape_up", "json_scrape_duration_seconds", "json_scrape_response_bytes"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in collected output, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// JSONPathMetric declares a single metric by pinpointing its value (and
+// optionally its labels) with JSONPath expressions, instead of relying on
+// the recursive "flatten every leaf, then filter" walk. This lets a
+// mapping reach into an array and pull out one field per matching
+// element, e.g. `$.services[?(@.type=='db')].latency_ms`.
+type JSONPathMetric struct {
+	Name    string            `yaml:"name"`
+	Help    string            `yaml:"help,omitempty"`
+	Type    MetricType        `yaml:"type,omitempty"`
+	Buckets []float64         `yaml:"buckets,omitempty"`
+	TTL     time.Duration     `yaml:"ttl,omitempty"`
+	Path    string            `yaml:"path"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+
+	valueExpr gval.Evaluable
+	// labelNames holds Labels' keys in a fixed, sorted order so that the
+	// same metric always appends its labels to targetCollector.labels in
+	// the same relative order across scrapes - ranging over Labels (a
+	// map) directly would randomize that order per call, and since
+	// newMetricFamily captures the label order from the first call, a
+	// later call appending them differently corrupts every series for
+	// that metric with mismatched label values.
+	labelNames []string
+	labelExprs map[string]gval.Evaluable
+}
+
+// compile parses Path and every label expression into reusable
+// evaluables, and validates the declared metric Type.
+func (m *JSONPathMetric) compile() error {
+	if m.Name == "" {
+		return fmt.Errorf("metric is missing a name")
+	}
+	if m.Path == "" {
+		return fmt.Errorf("metric %q is missing a path", m.Name)
+	}
+
+	switch m.Type {
+	case "", MetricTypeGauge, MetricTypeCounter, MetricTypeHistogram, MetricTypeUntyped:
+	default:
+		return fmt.Errorf("unknown metric type %q for metric %q", m.Type, m.Name)
+	}
+
+	valueExpr, err := jsonpath.New(m.Path)
+	if err != nil {
+		return fmt.Errorf("invalid jsonpath %q for metric %q: %s", m.Path, m.Name, err)
+	}
+	m.valueExpr = valueExpr
+
+	m.labelNames = make([]string, 0, len(m.Labels))
+	for label := range m.Labels {
+		m.labelNames = append(m.labelNames, label)
+	}
+	sort.Strings(m.labelNames)
+
+	m.labelExprs = make(map[string]gval.Evaluable, len(m.Labels))
+	for _, label := range m.labelNames {
+		expr, err := jsonpath.New(m.Labels[label])
+		if err != nil {
+			return fmt.Errorf("invalid jsonpath %q for label %q of metric %q: %s", m.Labels[label], label, m.Name, err)
+		}
+		m.labelExprs[label] = expr
+	}
+	return nil
+}
+
+// asList normalizes a JSONPath evaluation result to a slice: wildcarded
+// paths already evaluate to []interface{}, a single match does not.
+func asList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+// asFloat64 converts a JSON leaf value to a metric value the same way the
+// recursive extractor does.
+func asFloat64(v interface{}) (float64, bool) {
+	switch vv := v.(type) {
+	case float64:
+		return vv, true
+	case int:
+		return float64(vv), true
+	case bool:
+		if vv {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// extractJSONPathMetrics evaluates every configured JSONPathMetric against
+// root and records one series per matched value. It reports whether any
+// metrics were configured at all, so the caller can fall back to the
+// recursive walk when they weren't.
+func (t *targetCollector) extractJSONPathMetrics(root map[string]interface{}) bool {
+	if len(t.cfg.Metrics) == 0 {
+		return false
+	}
+
+	ctx := context.Background()
+	for _, jm := range t.cfg.Metrics {
+		rawValues, err := jm.valueExpr(ctx, root)
+		if err != nil {
+			if t.debug {
+				log.Println("jsonpath value expression failed for", jm.Name, ":", err)
+			}
+			continue
+		}
+		values := asList(rawValues)
+
+		labelValues := make(map[string][]interface{}, len(jm.labelNames))
+		for _, label := range jm.labelNames {
+			rawLabel, err := jm.labelExprs[label](ctx, root)
+			if err != nil {
+				if t.debug {
+					log.Println("jsonpath label expression failed for", label, "on metric", jm.Name, ":", err)
+				}
+				continue
+			}
+			labelValues[label] = asList(rawLabel)
+		}
+
+		for i, rawValue := range values {
+			value, ok := asFloat64(rawValue)
+			if !ok {
+				continue
+			}
+
+			added := 0
+			for _, label := range jm.labelNames {
+				list, ok := labelValues[label]
+				if !ok {
+					continue
+				}
+				value := ""
+				if i < len(list) {
+					value = fmt.Sprintf("%v", list[i])
+				}
+				t.addLabel(label, value)
+				added++
+			}
+
+			help := jm.Help
+			if help == "" {
+				help = jm.Name + helpSuffix
+			}
+			t.record(jm.Name, value, help, jm.Type, jm.Buckets, jm.TTL)
+
+			if added > 0 {
+				t.delLastLabels(added)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestJSONPathMetricCompileValidation(t *testing.T) {
+	cases := []struct {
+		name string
+		m    JSONPathMetric
+	}{
+		{"missing name", JSONPathMetric{Path: "$.foo"}},
+		{"missing path", JSONPathMetric{Name: "foo"}},
+		{"bad type", JSONPathMetric{Name: "foo", Path: "$.foo", Type: "weird"}},
+		{"bad path", JSONPathMetric{Name: "foo", Path: "$.["}},
+		{"bad label path", JSONPathMetric{Name: "foo", Path: "$.foo", Labels: map[string]string{"l": "$.["}}},
+	}
+	for _, c := range cases {
+		if err := c.m.compile(); err == nil {
+			t.Errorf("%s: expected compile() to return an error", c.name)
+		}
+	}
+}
+
+func scrapeCollectorBody(t *testing.T, c prometheus.Collector) string {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("failed to register collector: %s", err)
+	}
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestExtractJSONPathMetricsProducesOneSeriesPerMatch(t *testing.T) {
+	cfg := &TargetConfig{
+		Name:      "jp",
+		Namespace: "jp",
+		Metrics: []*JSONPathMetric{
+			{
+				Name:   "svc_latency_ms",
+				Path:   "$.services[*].latency",
+				Labels: map[string]string{"svc": "$.services[*].name"},
+			},
+		},
+	}
+	for _, m := range cfg.Metrics {
+		if err := m.compile(); err != nil {
+			t.Fatalf("compile: unexpected error: %s", err)
+		}
+	}
+
+	tc, err := newTargetCollector(cfg, 5*time.Second, false)
+	if err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	root := map[string]interface{}{
+		"services": []interface{}{
+			map[string]interface{}{"name": "a", "latency": 12.5},
+			map[string]interface{}{"name": "b", "latency": 7.0},
+		},
+	}
+
+	if ok := tc.extractJSONPathMetrics(root); !ok {
+		t.Fatal("expected extractJSONPathMetrics to report configured metrics")
+	}
+
+	body := scrapeCollectorBody(t, probeCollector{target: tc})
+	for _, want := range []string{
+		`jp_svc_latency_ms{svc="a"} 12.5`,
+		`jp_svc_latency_ms{svc="b"} 7`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in collected output, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestExtractJSONPathMetricsStableLabelOrderAcrossScrapes guards against a
+// metric with 2+ labels appending them in a different relative order on a
+// later scrape than on an earlier one for the same series - since
+// newMetricFamily fixes a metric's label order from the first observe()
+// call, a later mismatch makes every subsequent /metrics scrape fail with
+// "collected metric ... was collected before with the same name and label
+// values". Repeating the same extraction many times would eventually
+// surface a map-iteration-order bug if one were reintroduced.
+func TestExtractJSONPathMetricsStableLabelOrderAcrossScrapes(t *testing.T) {
+	cfg := &TargetConfig{
+		Name:      "jp",
+		Namespace: "jp",
+		Metrics: []*JSONPathMetric{
+			{
+				Name: "svc_latency_ms",
+				Path: "$.services[*].latency",
+				Labels: map[string]string{
+					"svc":    "$.services[*].name",
+					"region": "$.services[*].region",
+				},
+			},
+		},
+	}
+	for _, m := range cfg.Metrics {
+		if err := m.compile(); err != nil {
+			t.Fatalf("compile: unexpected error: %s", err)
+		}
+	}
+
+	tc, err := newTargetCollector(cfg, 5*time.Second, false)
+	if err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	root := map[string]interface{}{
+		"services": []interface{}{
+			map[string]interface{}{"name": "a", "region": "us", "latency": 12.5},
+			map[string]interface{}{"name": "b", "region": "eu", "latency": 7.0},
+		},
+	}
+
+	for i := 0; i < 20; i++ {
+		if ok := tc.extractJSONPathMetrics(root); !ok {
+			t.Fatalf("scrape %d: expected extractJSONPathMetrics to report configured metrics", i)
+		}
+		body := scrapeCollectorBody(t, probeCollector{target: tc})
+		for _, want := range []string{
+			`jp_svc_latency_ms{region="us",svc="a"} 12.5`,
+			`jp_svc_latency_ms{region="eu",svc="b"} 7`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("scrape %d: expected %q in collected output, got:\n%s", i, want, body)
+			}
+		}
+	}
+}
+
+func TestExtractJSONPathMetricsFallsBackWhenNoneConfigured(t *testing.T) {
+	cfg := &TargetConfig{Name: "jp", Namespace: "jp"}
+	tc, err := newTargetCollector(cfg, 5*time.Second, false)
+	if err != nil {
+		t.Fatalf("newTargetCollector: unexpected error: %s", err)
+	}
+
+	if ok := tc.extractJSONPathMetrics(map[string]interface{}{"foo": 1.0}); ok {
+		t.Error("expected extractJSONPathMetrics to report false when no metrics are configured, so the caller falls back to the recursive walk")
+	}
+}
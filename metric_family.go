@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelValueSep joins a series' label values into a single map key; it is
+// a control character so it won't collide with real label values.
+const labelValueSep = "\xff"
+
+// metricFamily wraps the single prometheus vector backing one metric name
+// for one target, and tracks when each of its label-value series was last
+// observed so it can be expired once its mapping's TTL elapses.
+type metricFamily struct {
+	mtype MetricType
+	ttl   time.Duration
+
+	gaugeVec   *prometheus.GaugeVec
+	counterVec *prometheus.CounterVec
+	histVec    *prometheus.HistogramVec
+
+	lastSeen map[string]time.Time
+
+	// prevValue holds the last raw (cumulative) value seen per counter
+	// series, so observe can Add the delta instead of the whole value -
+	// the source's counter is already cumulative, ours shouldn't double
+	// count it every scrape.
+	prevValue map[string]float64
+}
+
+// newMetricFamily builds the prometheus vector matching mtype. Untyped
+// mappings are exposed as gauges since Prometheus has no untyped vector.
+func newMetricFamily(namespace, name, help string, mtype MetricType, buckets []float64, labels []string) *metricFamily {
+	f := &metricFamily{
+		mtype:     mtype,
+		lastSeen:  make(map[string]time.Time),
+		prevValue: make(map[string]float64),
+	}
+	switch mtype {
+	case MetricTypeCounter:
+		f.counterVec = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	case MetricTypeHistogram:
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		f.histVec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	default:
+		f.mtype = MetricTypeGauge
+		f.gaugeVec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	}
+	return f
+}
+
+// collector returns the single prometheus.Collector backing this family.
+func (f *metricFamily) collector() prometheus.Collector {
+	switch f.mtype {
+	case MetricTypeCounter:
+		return f.counterVec
+	case MetricTypeHistogram:
+		return f.histVec
+	default:
+		return f.gaugeVec
+	}
+}
+
+// observe records value for labelvalues and marks the series as seen now,
+// so a later sweep won't expire it before ttl elapses.
+func (f *metricFamily) observe(labelvalues []string, value float64, ttl time.Duration) {
+	f.ttl = ttl
+	key := strings.Join(labelvalues, labelValueSep)
+	switch f.mtype {
+	case MetricTypeCounter:
+		// value is the source's own cumulative count, not a delta - add
+		// only what's new since the last scrape. A value lower than what
+		// we last saw means the source's counter reset (e.g. restarted),
+		// so treat it as a fresh start rather than going backwards.
+		delta := value
+		if prev, ok := f.prevValue[key]; ok && value >= prev {
+			delta = value - prev
+		}
+		if delta > 0 {
+			f.counterVec.WithLabelValues(labelvalues...).Add(delta)
+		}
+		f.prevValue[key] = value
+	case MetricTypeHistogram:
+		f.histVec.WithLabelValues(labelvalues...).Observe(value)
+	default:
+		f.gaugeVec.WithLabelValues(labelvalues...).Set(value)
+	}
+	f.lastSeen[key] = time.Now()
+}
+
+// sweep deletes any series that haven't been observed within the family's
+// TTL. A zero TTL means "never expire", matching the YAML `ttl: 0` value.
+func (f *metricFamily) sweep(now time.Time) {
+	if f.ttl <= 0 {
+		return
+	}
+	for key, seen := range f.lastSeen {
+		if now.Sub(seen) <= f.ttl {
+			continue
+		}
+		labelvalues := strings.Split(key, labelValueSep)
+		switch f.mtype {
+		case MetricTypeCounter:
+			f.counterVec.DeleteLabelValues(labelvalues...)
+			delete(f.prevValue, key)
+		case MetricTypeHistogram:
+			f.histVec.DeleteLabelValues(labelvalues...)
+		default:
+			f.gaugeVec.DeleteLabelValues(labelvalues...)
+		}
+		delete(f.lastSeen, key)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveCounterAddsDeltaNotRawValue(t *testing.T) {
+	f := newMetricFamily("test", "requests_total", "help", MetricTypeCounter, nil, []string{"label1"})
+
+	// A source reporting a stable cumulative count should not make our
+	// counter grow every time we happen to scrape it again.
+	f.observe([]string{"value1"}, 100, 0)
+	f.observe([]string{"value1"}, 100, 0)
+	f.observe([]string{"value1"}, 100, 0)
+
+	got := testutil.ToFloat64(f.counterVec.WithLabelValues("value1"))
+	if got != 100 {
+		t.Errorf("expected counter to read 100 after 3 identical scrapes, got %v", got)
+	}
+}
+
+func TestObserveCounterAccumulatesIncreases(t *testing.T) {
+	f := newMetricFamily("test", "requests_total", "help", MetricTypeCounter, nil, []string{"label1"})
+
+	f.observe([]string{"value1"}, 100, 0)
+	f.observe([]string{"value1"}, 140, 0)
+
+	got := testutil.ToFloat64(f.counterVec.WithLabelValues("value1"))
+	if got != 140 {
+		t.Errorf("expected counter to read 140 after a later higher scrape, got %v", got)
+	}
+}
+
+func TestObserveCounterHandlesSourceReset(t *testing.T) {
+	f := newMetricFamily("test", "requests_total", "help", MetricTypeCounter, nil, []string{"label1"})
+
+	f.observe([]string{"value1"}, 100, 0)
+	// Source restarted and its own counter went back to a smaller value.
+	f.observe([]string{"value1"}, 10, 0)
+
+	got := testutil.ToFloat64(f.counterVec.WithLabelValues("value1"))
+	if got != 110 {
+		t.Errorf("expected counter to read 110 (100 + fresh start at 10), got %v", got)
+	}
+}
+
+func TestSweepExpiresSeriesPastTTL(t *testing.T) {
+	f := newMetricFamily("test", "some_gauge", "help", MetricTypeGauge, nil, []string{"label1"})
+
+	f.observe([]string{"value1"}, 5, 10*time.Millisecond)
+	if _, ok := f.lastSeen["value1"]; !ok {
+		t.Fatalf("expected series to be tracked in lastSeen after observe")
+	}
+
+	f.sweep(time.Now().Add(time.Hour))
+
+	if _, ok := f.lastSeen["value1"]; ok {
+		t.Errorf("expected series to be expired from lastSeen after its TTL elapsed")
+	}
+}
+
+func TestSweepKeepsSeriesWithinTTL(t *testing.T) {
+	f := newMetricFamily("test", "some_gauge", "help", MetricTypeGauge, nil, []string{"label1"})
+
+	f.observe([]string{"value1"}, 5, time.Hour)
+	f.sweep(time.Now())
+
+	if _, ok := f.lastSeen["value1"]; !ok {
+		t.Errorf("expected series to survive a sweep that happens before its TTL elapses")
+	}
+}
+
+func TestSweepNeverExpiresWithZeroTTL(t *testing.T) {
+	f := newMetricFamily("test", "some_gauge", "help", MetricTypeGauge, nil, []string{"label1"})
+
+	f.observe([]string{"value1"}, 5, 0)
+	f.sweep(time.Now().Add(24 * time.Hour))
+
+	if _, ok := f.lastSeen["value1"]; !ok {
+		t.Errorf("expected a zero TTL to mean series are never expired")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeCollector adapts a single targetCollector to prometheus.Collector
+// so it can be registered into a private, request-scoped registry.
+type probeCollector struct {
+	target *targetCollector
+}
+
+func (p probeCollector) Describe(ch chan<- *prometheus.Desc) { p.target.describe(ch) }
+func (p probeCollector) Collect(ch chan<- prometheus.Metric) { p.target.collect(ch) }
+
+// newProbeHandler returns the handler for /probe?target=<url>&module=<name>,
+// following the blackbox_exporter/snmp_exporter convention: module picks
+// which configured target's mapping/label/regex settings to reuse, and
+// target overrides the URL actually scraped.
+func newProbeHandler(cfg *Config, timeout time.Duration, debug bool) http.HandlerFunc {
+	modules := make(map[string]*TargetConfig, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		modules[t.Name] = t
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		target := params.Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		module := modules[params.Get("module")]
+		if module == nil {
+			if name := params.Get("module"); name != "" {
+				http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+				return
+			}
+			if len(cfg.Targets) == 0 {
+				http.Error(w, "no modules configured", http.StatusBadRequest)
+				return
+			}
+			module = cfg.Targets[0]
+		}
+
+		// Scrape the requested URL using the module's settings, rather
+		// than whatever static URL the module itself was configured with.
+		probeCfg := *module
+		probeCfg.URL = target
+
+		tc, err := newTargetCollector(&probeCfg, timeout, debug)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to build collector for module %q: %s", probeCfg.Name, err), http.StatusInternalServerError)
+			return
+		}
+		tc.refresh()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(probeCollector{target: tc})
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbeHandlerScrapesRequestedTarget(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, `{"probe_metric": 42}`)
+	}))
+	defer ts.Close()
+
+	cfg := &Config{Targets: []*TargetConfig{{Name: "mod1", URL: ts.URL, Namespace: "probe"}}}
+	handler := newProbeHandler(cfg, 5*time.Second, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?target="+url.QueryEscape(ts.URL)+"&module=mod1", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "probe_probe_metric 42") {
+		t.Errorf("expected probe_probe_metric 42 in response, got:\n%s", body)
+	}
+
+	// newTargetCollector must not scrape the target URL a second time
+	// (once for value-labels, once for the actual probe) when the
+	// module has no value_labels configured.
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("expected exactly 1 scrape of the target URL, got %d", got)
+	}
+}
+
+func TestProbeHandlerRequiresTarget(t *testing.T) {
+	cfg := &Config{Targets: []*TargetConfig{{Name: "mod1", URL: "http://example.invalid/"}}}
+	handler := newProbeHandler(cfg, 5*time.Second, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when target is missing, got %d", w.Code)
+	}
+}
+
+func TestProbeHandlerUnknownModule(t *testing.T) {
+	cfg := &Config{Targets: []*TargetConfig{{Name: "mod1", URL: "http://example.invalid/"}}}
+	handler := newProbeHandler(cfg, 5*time.Second, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?target=http://example.invalid/&module=nope", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown module, got %d", w.Code)
+	}
+}
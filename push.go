@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// readInput returns the JSON payload for --once mode: from stdin when
+// path is "-", otherwise from the named file.
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// parseGrouping turns a comma separated key=value list into the grouping
+// key push.Pusher.Grouping expects one pair at a time.
+func parseGrouping(grouping string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if grouping == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(grouping, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid --push.grouping pair %q, expected key=value", pair)
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// runOnce reads a single JSON payload - from inputFile (or "-" for
+// stdin) if set, otherwise by scraping the target's own URL once -
+// extracts its metrics, and pushes them to a Pushgateway instead of
+// serving them over HTTP. It's meant for short-lived jobs and devices
+// behind a NAT that can't be scraped directly.
+func runOnce(cfg *Config, timeout time.Duration, debug bool, inputFile string, gateway string, job string, grouping string) error {
+	if gateway == "" {
+		return fmt.Errorf("--once requires --push.gateway")
+	}
+	if job == "" {
+		return fmt.Errorf("--once requires --push.job")
+	}
+	if len(cfg.Targets) != 1 {
+		return fmt.Errorf("--once requires exactly one target, got %d", len(cfg.Targets))
+	}
+
+	groupingLabels, err := parseGrouping(grouping)
+	if err != nil {
+		return err
+	}
+
+	tc, err := newTargetCollector(cfg.Targets[0], timeout, debug)
+	if err != nil {
+		return err
+	}
+
+	if inputFile != "" {
+		body, err := readInput(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read input: %s", err)
+		}
+		// A parse failure still leaves tc.up at 0, and that's what gets
+		// pushed below - same "report the failure, don't abort" contract
+		// as a live refresh() failing to reach the target.
+		if err := tc.ingest(body); err != nil {
+			log.Println("Failed to unmarshal JSON into struct:", err)
+		}
+	} else {
+		tc.refresh()
+	}
+
+	pusher := push.New(gateway, job).Collector(probeCollector{target: tc})
+	for name, value := range groupingLabels {
+		pusher = pusher.Grouping(name, value)
+	}
+	return pusher.Push()
+}
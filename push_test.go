@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	commonconfig "github.com/prometheus/common/config"
+)
+
+func TestParseGrouping(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"instance=host1", map[string]string{"instance": "host1"}, false},
+		{"instance=host1,env=prod", map[string]string{"instance": "host1", "env": "prod"}, false},
+		{"bad", nil, true},
+		{"=value", nil, true},
+		{"key=", nil, true},
+	}
+	for _, c := range cases {
+		got, err := parseGrouping(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseGrouping(%q): expected an error, got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGrouping(%q): unexpected error: %s", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseGrouping(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestReadInputFromFile(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "push_test_input")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	want := []byte(`{"foo": 1}`)
+	if _, err := tmp.Write(want); err != nil {
+		t.Fatalf("failed to write temp file: %s", err)
+	}
+	tmp.Close()
+
+	got, err := readInput(tmp.Name())
+	if err != nil {
+		t.Fatalf("readInput: unexpected error: %s", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("readInput = %q, want %q", got, want)
+	}
+}
+
+func TestRunOnceRequiresGatewayAndJob(t *testing.T) {
+	cfg := &Config{Targets: []*TargetConfig{{Name: "t", URL: "http://example.invalid/"}}}
+
+	if err := runOnce(cfg, time.Second, false, "", "", "job", ""); err == nil {
+		t.Error("expected an error when --push.gateway is empty")
+	}
+	if err := runOnce(cfg, time.Second, false, "", "http://example.invalid/", "", ""); err == nil {
+		t.Error("expected an error when --push.job is empty")
+	}
+}
+
+func TestRunOncePushesFromInputFileWithoutAURL(t *testing.T) {
+	var pushedBody []byte
+	pgw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		pushedBody = body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer pgw.Close()
+
+	tmp, err := ioutil.TempFile("", "push_test_input")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write([]byte(`{"once_metric": 7}`)); err != nil {
+		t.Fatalf("failed to write temp file: %s", err)
+	}
+	tmp.Close()
+
+	// No URL at all - this is the --once --input.file case the fix
+	// covers, where the target isn't scraped over HTTP.
+	cfg := defaultConfig([]string{""}, "once", nil, nil, "", "", "", "", false, true, commonconfig.HTTPClientConfig{})
+
+	if err := runOnce(cfg, time.Second, false, tmp.Name(), pgw.URL, "myjob", "instance=host1"); err != nil {
+		t.Fatalf("runOnce: unexpected error: %s", err)
+	}
+	if len(pushedBody) == 0 {
+		t.Fatal("expected a non-empty push to the pushgateway")
+	}
+}